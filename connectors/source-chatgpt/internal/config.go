@@ -0,0 +1,75 @@
+// Copyright 2023 Linkall Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	cdkgo "github.com/vanus-labs/cdk-go"
+
+	"github.com/vanus-labs/vanus-connect/connectors/source-chatgpt/internal/provider"
+	"github.com/vanus-labs/vanus-connect/connectors/source-chatgpt/internal/tracing"
+)
+
+const (
+	defaultPort  = 8080
+	defaultModel = "gpt-3.5-turbo"
+)
+
+func NewConfig() cdkgo.ConfigAccessor {
+	return &chatGPTConfig{}
+}
+
+type chatGPTConfig struct {
+	cdkgo.SourceConfig `json:",inline" yaml:",inline"`
+
+	Port   int    `json:"port" yaml:"port"`
+	APIKey string `json:"api_key" yaml:"api_key"`
+	Model  string `json:"model" yaml:"model"`
+	// Stream enables Server-Sent Events streaming mode for ChatGPT
+	// completions. It can be overridden per-request with the
+	// vanus-stream header.
+	Stream bool `json:"stream" yaml:"stream"`
+	// Tracing configures OpenTelemetry distributed tracing for this
+	// connector. Tracing is disabled when left unset.
+	Tracing tracing.Config `json:"tracing" yaml:"tracing"`
+	// Routing configures the set of LLM backends the source can route to.
+	// When left unset, it falls back to a single OpenAI provider built
+	// from APIKey/Model above.
+	Routing provider.RoutingConfig `json:"routing" yaml:"routing"`
+	// TrustedProxies lists the CIDRs of reverse proxies allowed to set
+	// X-Real-IP/X-Forwarded-For when resolving the real client IP.
+	// Requests from any other peer have these headers ignored.
+	TrustedProxies []string `json:"trusted_proxies" yaml:"trusted_proxies"`
+	// RateLimit configures per-IP and global request throttling.
+	RateLimit RateLimitConfig `json:"rate_limit" yaml:"rate_limit"`
+}
+
+func (c *chatGPTConfig) GetSecret() cdkgo.SecretAccessor {
+	return nil
+}
+
+func (c *chatGPTConfig) Init() {
+	if c.Port <= 0 {
+		c.Port = defaultPort
+	}
+	if c.Model == "" {
+		c.Model = defaultModel
+	}
+	if len(c.Routing.Providers) == 0 {
+		c.Routing.Providers = []provider.Config{
+			{Kind: "openai", Name: "openai", APIKey: c.APIKey, Model: c.Model},
+		}
+	}
+	c.RateLimit.init()
+}
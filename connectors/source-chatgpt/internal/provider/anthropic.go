@@ -0,0 +1,151 @@
+// Copyright 2023 Linkall Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/vanus-labs/vanus-connect/connectors/source-chatgpt/internal/tracing"
+)
+
+const (
+	anthropicDefaultEndpoint = "https://api.anthropic.com/v1/messages"
+	anthropicVersion         = "2023-06-01"
+	anthropicMaxTokens       = 1024
+)
+
+type anthropicRequest struct {
+	Model     string             `json:"model"`
+	MaxTokens int                `json:"max_tokens"`
+	Messages  []anthropicMessage `json:"messages"`
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Text string `json:"text"`
+	} `json:"content"`
+}
+
+// anthropicProvider implements LLMProvider against Anthropic's Messages API.
+// Anthropic streams `content_block_delta` events rather than the
+// OpenAI-shaped chunks the rest of this package parses, so Stream doesn't
+// parse the wire format at all: it issues one blocking Complete call and
+// emits its result as a single terminal Delta.
+type anthropicProvider struct {
+	name     string
+	endpoint string
+	apiKey   string
+	model    string
+	client   *http.Client
+	breaker  *breaker
+}
+
+func newAnthropicProvider(name string, cfg Config, b *breaker) *anthropicProvider {
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = anthropicDefaultEndpoint
+	}
+	return &anthropicProvider{
+		name:     name,
+		endpoint: endpoint,
+		apiKey:   cfg.APIKey,
+		model:    cfg.Model,
+		client:   newHTTPClient(),
+		breaker:  b,
+	}
+}
+
+func (p *anthropicProvider) Name() string {
+	return p.name
+}
+
+func (p *anthropicProvider) Healthy() bool {
+	return p.breaker.Allow()
+}
+
+func (p *anthropicProvider) Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "chatgpt.http.request", trace.WithAttributes(
+		attribute.String("provider", p.name), attribute.String("model", p.model),
+	))
+	defer span.End()
+
+	body, err := json.Marshal(anthropicRequest{
+		Model:     p.model,
+		MaxTokens: anthropicMaxTokens,
+		Messages:  []anthropicMessage{{Role: "user", Content: req.Prompt}},
+	})
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return CompletionResponse{}, fmt.Errorf("failed to marshal anthropic request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return CompletionResponse{}, fmt.Errorf("failed to build anthropic request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", p.apiKey)
+	httpReq.Header.Set("anthropic-version", anthropicVersion)
+	tracing.InjectHTTPHeaders(ctx, httpReq.Header)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		recordUpstreamFailure(ctx, p.breaker, 0)
+		span.SetStatus(codes.Error, err.Error())
+		return CompletionResponse{}, fmt.Errorf("failed to call %s: %w", p.name, err)
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return CompletionResponse{}, fmt.Errorf("failed to read %s response: %w", p.name, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		recordUpstreamFailure(ctx, p.breaker, resp.StatusCode)
+		span.SetStatus(codes.Error, fmt.Sprintf("status %d", resp.StatusCode))
+		return CompletionResponse{}, fmt.Errorf("%s returned status %d: %s", p.name, resp.StatusCode, string(respBody))
+	}
+
+	var completion anthropicResponse
+	if err = json.Unmarshal(respBody, &completion); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return CompletionResponse{}, fmt.Errorf("failed to unmarshal %s response: %w", p.name, err)
+	}
+	if len(completion.Content) == 0 {
+		span.SetStatus(codes.Error, "no content returned")
+		return CompletionResponse{}, fmt.Errorf("%s returned no content", p.name)
+	}
+	p.breaker.RecordSuccess()
+	span.SetStatus(codes.Ok, "")
+	return CompletionResponse{Content: completion.Content[0].Text}, nil
+}
+
+func (p *anthropicProvider) Stream(ctx context.Context, req CompletionRequest) (<-chan Delta, error) {
+	return completeAsStream(ctx, p.Complete, req)
+}
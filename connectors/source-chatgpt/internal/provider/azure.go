@@ -0,0 +1,51 @@
+// Copyright 2023 Linkall Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"net/http"
+)
+
+// azureProvider implements LLMProvider against Azure OpenAI. Azure serves
+// the same chat completions wire format as OpenAI but under a
+// deployment-scoped URL and with the API key passed as api-key rather than
+// a bearer token, so it reuses openAIProvider's request/response handling.
+type azureProvider struct {
+	*openAIProvider
+}
+
+func newAzureProvider(name string, cfg Config, b *breaker) *azureProvider {
+	p := newOpenAIProvider(name, cfg, b)
+	return &azureProvider{openAIProvider: p}
+}
+
+func (p *azureProvider) newRequest(ctx context.Context, prompt string, stream bool) (*http.Request, error) {
+	req, err := p.openAIProvider.newRequest(ctx, prompt, stream)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Del("Authorization")
+	req.Header.Set("api-key", p.apiKey)
+	return req, nil
+}
+
+func (p *azureProvider) Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
+	return p.completeWithRequest(ctx, p.newRequest, req)
+}
+
+func (p *azureProvider) Stream(ctx context.Context, req CompletionRequest) (<-chan Delta, error) {
+	return p.streamWithRequest(ctx, p.newRequest, req)
+}
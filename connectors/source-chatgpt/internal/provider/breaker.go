@@ -0,0 +1,103 @@
+// Copyright 2023 Linkall Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// failureThreshold is how many consecutive failures trip the breaker.
+	failureThreshold = 3
+	minBackoff       = time.Second
+	maxBackoff       = 30 * time.Second
+)
+
+// breaker is a simple per-provider circuit breaker: it trips open after
+// failureThreshold consecutive failures on 401/429/5xx responses, backing
+// off exponentially with jitter before letting requests through again.
+type breaker struct {
+	mu          sync.Mutex
+	failures    int
+	openUntil   time.Time
+	nextBackoff time.Duration
+}
+
+func newBreaker() *breaker {
+	return &breaker{nextBackoff: minBackoff}
+}
+
+// Allow reports whether a request should be attempted right now.
+func (b *breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return time.Now().After(b.openUntil)
+}
+
+// RecordSuccess closes the breaker and resets the backoff.
+func (b *breaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.nextBackoff = minBackoff
+	b.openUntil = time.Time{}
+}
+
+// RecordFailure counts a failed call, tripping the breaker once
+// failureThreshold consecutive failures on a retryable status are seen.
+// statusCode is 0 for failures that never got an HTTP response (timeouts,
+// connection errors).
+func (b *breaker) RecordFailure(statusCode int) {
+	if !isRetryable(statusCode) {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.failures < failureThreshold {
+		return
+	}
+	backoff := b.nextBackoff
+	jitter := time.Duration(rand.Int63n(int64(backoff) / 2))
+	b.openUntil = time.Now().Add(backoff + jitter)
+	b.nextBackoff *= 2
+	if b.nextBackoff > maxBackoff {
+		b.nextBackoff = maxBackoff
+	}
+}
+
+// recordUpstreamFailure records a failed call on b unless ctx was itself
+// canceled or timed out by the caller. An outbound request aborted because
+// the inbound client hung up is not evidence the provider is unhealthy, so
+// it must never count toward tripping the breaker.
+func recordUpstreamFailure(ctx context.Context, b *breaker, statusCode int) {
+	if ctx.Err() != nil {
+		return
+	}
+	b.RecordFailure(statusCode)
+}
+
+func isRetryable(statusCode int) bool {
+	if statusCode == 0 {
+		return true
+	}
+	return statusCode == http.StatusUnauthorized ||
+		statusCode == http.StatusTooManyRequests ||
+		statusCode >= http.StatusInternalServerError
+}
@@ -0,0 +1,103 @@
+// Copyright 2023 Linkall Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestBreakerTripsAfterThreshold(t *testing.T) {
+	b := newBreaker()
+	for i := 0; i < failureThreshold-1; i++ {
+		b.RecordFailure(http.StatusInternalServerError)
+		if !b.Allow() {
+			t.Fatalf("breaker tripped after only %d failures, want %d", i+1, failureThreshold)
+		}
+	}
+	b.RecordFailure(http.StatusInternalServerError)
+	if b.Allow() {
+		t.Fatal("breaker did not trip after failureThreshold consecutive failures")
+	}
+}
+
+func TestBreakerRecordSuccessResets(t *testing.T) {
+	b := newBreaker()
+	for i := 0; i < failureThreshold; i++ {
+		b.RecordFailure(http.StatusInternalServerError)
+	}
+	if b.Allow() {
+		t.Fatal("expected breaker to be tripped before RecordSuccess")
+	}
+	b.RecordSuccess()
+	if !b.Allow() {
+		t.Fatal("RecordSuccess did not close the breaker")
+	}
+	if b.failures != 0 || b.nextBackoff != minBackoff {
+		t.Fatalf("RecordSuccess did not reset internal state: failures=%d nextBackoff=%v", b.failures, b.nextBackoff)
+	}
+}
+
+func TestBreakerIgnoresNonRetryableStatus(t *testing.T) {
+	b := newBreaker()
+	for i := 0; i < failureThreshold+2; i++ {
+		b.RecordFailure(http.StatusBadRequest)
+	}
+	if !b.Allow() {
+		t.Fatal("non-retryable status codes must never trip the breaker")
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	cases := map[int]bool{
+		0:                              true,
+		http.StatusUnauthorized:        true,
+		http.StatusTooManyRequests:     true,
+		http.StatusInternalServerError: true,
+		http.StatusBadGateway:          true,
+		http.StatusBadRequest:          false,
+		http.StatusOK:                  false,
+		http.StatusNotFound:            false,
+	}
+	for status, want := range cases {
+		if got := isRetryable(status); got != want {
+			t.Errorf("isRetryable(%d) = %v, want %v", status, got, want)
+		}
+	}
+}
+
+func TestRecordUpstreamFailureSkipsOnClientCancel(t *testing.T) {
+	b := newBreaker()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < failureThreshold+2; i++ {
+		recordUpstreamFailure(ctx, b, 0)
+	}
+	if !b.Allow() {
+		t.Fatal("a canceled caller context must not trip the breaker")
+	}
+}
+
+func TestRecordUpstreamFailureCountsGenuineFailures(t *testing.T) {
+	b := newBreaker()
+	ctx := context.Background()
+	for i := 0; i < failureThreshold; i++ {
+		recordUpstreamFailure(ctx, b, http.StatusInternalServerError)
+	}
+	if b.Allow() {
+		t.Fatal("genuine upstream failures must still trip the breaker")
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2023 Linkall Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/vanus-labs/vanus-connect/connectors/source-chatgpt/internal/tracing"
+)
+
+const cohereDefaultEndpoint = "https://api.cohere.ai/v1/chat"
+
+type cohereRequest struct {
+	Model   string `json:"model"`
+	Message string `json:"message"`
+}
+
+type cohereResponse struct {
+	Text string `json:"text"`
+}
+
+// cohereProvider implements LLMProvider against Cohere's chat API. The
+// non-streaming endpoint already returns the full reply in one response
+// body, so there's no SSE format to parse here at all: Stream issues one
+// blocking Complete call and emits its result as a single terminal Delta.
+type cohereProvider struct {
+	name     string
+	endpoint string
+	apiKey   string
+	model    string
+	client   *http.Client
+	breaker  *breaker
+}
+
+func newCohereProvider(name string, cfg Config, b *breaker) *cohereProvider {
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = cohereDefaultEndpoint
+	}
+	return &cohereProvider{
+		name:     name,
+		endpoint: endpoint,
+		apiKey:   cfg.APIKey,
+		model:    cfg.Model,
+		client:   newHTTPClient(),
+		breaker:  b,
+	}
+}
+
+func (p *cohereProvider) Name() string {
+	return p.name
+}
+
+func (p *cohereProvider) Healthy() bool {
+	return p.breaker.Allow()
+}
+
+func (p *cohereProvider) Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "chatgpt.http.request", trace.WithAttributes(
+		attribute.String("provider", p.name), attribute.String("model", p.model),
+	))
+	defer span.End()
+
+	body, err := json.Marshal(cohereRequest{Model: p.model, Message: req.Prompt})
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return CompletionResponse{}, fmt.Errorf("failed to marshal cohere request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return CompletionResponse{}, fmt.Errorf("failed to build cohere request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	tracing.InjectHTTPHeaders(ctx, httpReq.Header)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		recordUpstreamFailure(ctx, p.breaker, 0)
+		span.SetStatus(codes.Error, err.Error())
+		return CompletionResponse{}, fmt.Errorf("failed to call %s: %w", p.name, err)
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return CompletionResponse{}, fmt.Errorf("failed to read %s response: %w", p.name, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		recordUpstreamFailure(ctx, p.breaker, resp.StatusCode)
+		span.SetStatus(codes.Error, fmt.Sprintf("status %d", resp.StatusCode))
+		return CompletionResponse{}, fmt.Errorf("%s returned status %d: %s", p.name, resp.StatusCode, string(respBody))
+	}
+
+	var completion cohereResponse
+	if err = json.Unmarshal(respBody, &completion); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return CompletionResponse{}, fmt.Errorf("failed to unmarshal %s response: %w", p.name, err)
+	}
+	p.breaker.RecordSuccess()
+	span.SetStatus(codes.Ok, "")
+	return CompletionResponse{Content: completion.Text}, nil
+}
+
+func (p *cohereProvider) Stream(ctx context.Context, req CompletionRequest) (<-chan Delta, error) {
+	return completeAsStream(ctx, p.Complete, req)
+}
@@ -0,0 +1,269 @@
+// Copyright 2023 Linkall Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/vanus-labs/cdk-go/log"
+
+	"github.com/vanus-labs/vanus-connect/connectors/source-chatgpt/internal/tracing"
+)
+
+const (
+	openAIDefaultEndpoint = "https://api.openai.com/v1/chat/completions"
+	streamDonePayload     = "[DONE]"
+)
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatCompletionRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+	Stream   bool          `json:"stream,omitempty"`
+}
+
+type chatCompletionResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+type chatCompletionChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+// openAIProvider implements LLMProvider against OpenAI's chat completions
+// API. Azure OpenAI uses the same wire format through a differently shaped
+// URL, see azure.go.
+type openAIProvider struct {
+	name     string
+	endpoint string
+	apiKey   string
+	model    string
+	client   *http.Client
+	breaker  *breaker
+}
+
+func newOpenAIProvider(name string, cfg Config, b *breaker) *openAIProvider {
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = openAIDefaultEndpoint
+	}
+	return &openAIProvider{
+		name:     name,
+		endpoint: endpoint,
+		apiKey:   cfg.APIKey,
+		model:    cfg.Model,
+		client:   newHTTPClient(),
+		breaker:  b,
+	}
+}
+
+func (p *openAIProvider) Name() string {
+	return p.name
+}
+
+func (p *openAIProvider) Healthy() bool {
+	return p.breaker.Allow()
+}
+
+func (p *openAIProvider) newRequest(ctx context.Context, prompt string, stream bool) (*http.Request, error) {
+	body, err := json.Marshal(chatCompletionRequest{
+		Model:    p.model,
+		Messages: []chatMessage{{Role: "user", Content: prompt}},
+		Stream:   stream,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal chat completion request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build chat completion request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	tracing.InjectHTTPHeaders(ctx, req.Header)
+	return req, nil
+}
+
+func (p *openAIProvider) Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
+	return p.completeWithRequest(ctx, p.newRequest, req)
+}
+
+// completeWithRequest runs a blocking completion using newReq to build the
+// outbound HTTP request, letting provider variants such as azureProvider
+// override only how the request is authenticated/addressed.
+func (p *openAIProvider) completeWithRequest(
+	ctx context.Context,
+	newReq func(context.Context, string, bool) (*http.Request, error),
+	req CompletionRequest,
+) (CompletionResponse, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "chatgpt.http.request", trace.WithAttributes(
+		attribute.String("provider", p.name), attribute.String("model", p.model),
+	))
+	defer span.End()
+
+	httpReq, err := newReq(ctx, req.Prompt, false)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return CompletionResponse{}, err
+	}
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		recordUpstreamFailure(ctx, p.breaker, 0)
+		span.SetStatus(codes.Error, err.Error())
+		return CompletionResponse{}, fmt.Errorf("failed to call %s: %w", p.name, err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return CompletionResponse{}, fmt.Errorf("failed to read %s response: %w", p.name, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		recordUpstreamFailure(ctx, p.breaker, resp.StatusCode)
+		span.SetStatus(codes.Error, fmt.Sprintf("status %d", resp.StatusCode))
+		return CompletionResponse{}, fmt.Errorf("%s returned status %d: %s", p.name, resp.StatusCode, string(body))
+	}
+
+	var completion chatCompletionResponse
+	if err = json.Unmarshal(body, &completion); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return CompletionResponse{}, fmt.Errorf("failed to unmarshal %s response: %w", p.name, err)
+	}
+	if len(completion.Choices) == 0 {
+		span.SetStatus(codes.Error, "no choices returned")
+		return CompletionResponse{}, fmt.Errorf("%s returned no choices", p.name)
+	}
+	p.breaker.RecordSuccess()
+	span.SetStatus(codes.Ok, "")
+	return CompletionResponse{Content: completion.Choices[0].Message.Content}, nil
+}
+
+func (p *openAIProvider) Stream(ctx context.Context, req CompletionRequest) (<-chan Delta, error) {
+	return p.streamWithRequest(ctx, p.newRequest, req)
+}
+
+// streamWithRequest mirrors completeWithRequest for the streaming path.
+func (p *openAIProvider) streamWithRequest(
+	ctx context.Context,
+	newReq func(context.Context, string, bool) (*http.Request, error),
+	req CompletionRequest,
+) (<-chan Delta, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "chatgpt.http.request", trace.WithAttributes(
+		attribute.String("provider", p.name), attribute.String("model", p.model), attribute.Bool("stream", true),
+	))
+
+	httpReq, err := newReq(ctx, req.Prompt, true)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.End()
+		return nil, err
+	}
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		recordUpstreamFailure(ctx, p.breaker, 0)
+		span.SetStatus(codes.Error, err.Error())
+		span.End()
+		return nil, fmt.Errorf("failed to call %s: %w", p.name, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		recordUpstreamFailure(ctx, p.breaker, resp.StatusCode)
+		span.SetStatus(codes.Error, fmt.Sprintf("status %d", resp.StatusCode))
+		span.End()
+		return nil, fmt.Errorf("%s returned status %d: %s", p.name, resp.StatusCode, string(body))
+	}
+
+	deltas := make(chan Delta, 16)
+	go func() {
+		defer span.End()
+		defer close(deltas)
+		defer resp.Body.Close()
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || !strings.HasPrefix(line, "data:") {
+				continue
+			}
+			payload := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			if payload == streamDonePayload {
+				p.breaker.RecordSuccess()
+				span.SetStatus(codes.Ok, "")
+				return
+			}
+			var chunk chatCompletionChunk
+			if err = json.Unmarshal([]byte(payload), &chunk); err != nil {
+				log.Warning("failed to unmarshal stream chunk", map[string]interface{}{
+					log.KeyError: err,
+					"provider":   p.name,
+				})
+				continue
+			}
+			if len(chunk.Choices) == 0 {
+				continue
+			}
+			choice := chunk.Choices[0]
+			select {
+			case deltas <- Delta{Content: choice.Delta.Content, FinishReason: choice.FinishReason}:
+			case <-ctx.Done():
+				return
+			}
+		}
+		// The loop above only returns normally on an explicit [DONE]
+		// terminator; reaching here means the body ended before one was
+		// seen, whether scanner.Err() reports a read error or the
+		// connection was merely closed early (bufio.Scanner reports a
+		// clean EOF as a nil error). Either way the stream is truncated,
+		// not complete, so the caller must be told rather than silently
+		// handed a partial completion.
+		streamErr := scanner.Err()
+		if streamErr == nil {
+			streamErr = fmt.Errorf("stream ended before a %s terminator was seen", streamDonePayload)
+		}
+		recordUpstreamFailure(ctx, p.breaker, 0)
+		span.SetStatus(codes.Error, streamErr.Error())
+		log.Warning("stream ended with error", map[string]interface{}{
+			log.KeyError: streamErr,
+			"provider":   p.name,
+		})
+		select {
+		case deltas <- Delta{Err: streamErr}:
+		case <-ctx.Done():
+		}
+	}()
+	return deltas, nil
+}
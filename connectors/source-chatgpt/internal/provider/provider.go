@@ -0,0 +1,118 @@
+// Copyright 2023 Linkall Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package provider abstracts away the specific LLM backend (OpenAI, Azure
+// OpenAI, Anthropic, Cohere, ...) behind a common completion interface, so
+// the ChatGPT source can route a request to one of several backends and
+// fail over between them.
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// CompletionRequest is a model-agnostic chat completion request.
+type CompletionRequest struct {
+	Prompt string
+}
+
+// CompletionResponse is a model-agnostic chat completion result.
+type CompletionResponse struct {
+	Content string
+}
+
+// Delta is a single streamed chunk of a completion. FinishReason is only set
+// on the terminal delta of a request. Err is set instead of Content/
+// FinishReason when the upstream stream ended prematurely (the connection
+// dropped or the body was truncated before a terminator was seen); callers
+// must treat the stream as failed rather than complete when it's set.
+type Delta struct {
+	Content      string
+	FinishReason string
+	Err          error
+}
+
+// LLMProvider is a single LLM backend.
+type LLMProvider interface {
+	Name() string
+	Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error)
+	Stream(ctx context.Context, req CompletionRequest) (<-chan Delta, error)
+	// Healthy reports whether this provider's circuit breaker currently
+	// allows requests through.
+	Healthy() bool
+}
+
+// Config describes a single configured backend.
+type Config struct {
+	// Kind selects the backend implementation: "openai", "azure",
+	// "anthropic" or "cohere".
+	Kind string `json:"kind" yaml:"kind"`
+	// Name identifies this provider in routing decisions and the /healthz
+	// report. Defaults to Kind when empty.
+	Name     string `json:"name" yaml:"name"`
+	APIKey   string `json:"api_key" yaml:"api_key"`
+	Endpoint string `json:"endpoint" yaml:"endpoint"`
+	Model    string `json:"model" yaml:"model"`
+}
+
+// RoutingConfig configures the set of backends the source can route to and
+// how it picks between them.
+type RoutingConfig struct {
+	// Strategy is "priority" (try Providers in order), "round_robin", or
+	// "least_latency". Defaults to "priority".
+	Strategy  string   `json:"strategy" yaml:"strategy"`
+	Providers []Config `json:"providers" yaml:"providers"`
+}
+
+func newHTTPClient() *http.Client {
+	return &http.Client{Timeout: 30 * time.Second}
+}
+
+// completeAsStream adapts a provider that only supports blocking completions
+// into the Stream API by running complete and emitting its result as a
+// single terminal delta.
+func completeAsStream(ctx context.Context, complete func(context.Context, CompletionRequest) (CompletionResponse, error), req CompletionRequest) (<-chan Delta, error) {
+	resp, err := complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	deltas := make(chan Delta, 1)
+	deltas <- Delta{Content: resp.Content, FinishReason: "stop"}
+	close(deltas)
+	return deltas, nil
+}
+
+// New builds the LLMProvider for a single Config entry.
+func New(cfg Config) (LLMProvider, error) {
+	name := cfg.Name
+	if name == "" {
+		name = cfg.Kind
+	}
+	breaker := newBreaker()
+	switch cfg.Kind {
+	case "", "openai":
+		return newOpenAIProvider(name, cfg, breaker), nil
+	case "azure":
+		return newAzureProvider(name, cfg, breaker), nil
+	case "anthropic":
+		return newAnthropicProvider(name, cfg, breaker), nil
+	case "cohere":
+		return newCohereProvider(name, cfg, breaker), nil
+	default:
+		return nil, fmt.Errorf("unknown provider kind %q", cfg.Kind)
+	}
+}
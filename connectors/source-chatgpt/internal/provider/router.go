@@ -0,0 +1,143 @@
+// Copyright 2023 Linkall Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StrategyPriority     = "priority"
+	StrategyRoundRobin   = "round_robin"
+	StrategyLeastLatency = "least_latency"
+)
+
+// Router picks an LLMProvider per request based on a routing strategy and
+// transparently fails over to the next healthy provider before returning an
+// error to the caller.
+type Router struct {
+	strategy  string
+	providers []*trackedProvider
+	next      uint64 // round-robin cursor
+}
+
+type trackedProvider struct {
+	LLMProvider
+	latency atomic.Int64 // last observed call latency, nanoseconds
+}
+
+// NewRouter builds a Router from cfg. Providers are tried in the order given
+// by cfg.Providers for the "priority" strategy.
+func NewRouter(cfg RoutingConfig) (*Router, error) {
+	if len(cfg.Providers) == 0 {
+		return nil, fmt.Errorf("routing requires at least one provider")
+	}
+	strategy := cfg.Strategy
+	if strategy == "" {
+		strategy = StrategyPriority
+	}
+	providers := make([]*trackedProvider, 0, len(cfg.Providers))
+	for _, pc := range cfg.Providers {
+		p, err := New(pc)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, &trackedProvider{LLMProvider: p})
+	}
+	return &Router{strategy: strategy, providers: providers}, nil
+}
+
+// Health reports the health of every configured provider, keyed by name.
+func (r *Router) Health() map[string]bool {
+	health := make(map[string]bool, len(r.providers))
+	for _, p := range r.providers {
+		health[p.Name()] = p.Healthy()
+	}
+	return health
+}
+
+// order returns the providers to try, in the order the active strategy
+// dictates.
+func (r *Router) order() []*trackedProvider {
+	switch r.strategy {
+	case StrategyRoundRobin:
+		start := int(atomic.AddUint64(&r.next, 1)-1) % len(r.providers)
+		ordered := make([]*trackedProvider, 0, len(r.providers))
+		for i := 0; i < len(r.providers); i++ {
+			ordered = append(ordered, r.providers[(start+i)%len(r.providers)])
+		}
+		return ordered
+	case StrategyLeastLatency:
+		ordered := append([]*trackedProvider(nil), r.providers...)
+		sortByLatency(ordered)
+		return ordered
+	default: // StrategyPriority
+		return r.providers
+	}
+}
+
+func sortByLatency(providers []*trackedProvider) {
+	for i := 1; i < len(providers); i++ {
+		for j := i; j > 0 && providers[j].latency.Load() < providers[j-1].latency.Load(); j-- {
+			providers[j], providers[j-1] = providers[j-1], providers[j]
+		}
+	}
+}
+
+// Complete tries each provider in strategy order, skipping unhealthy ones,
+// and fails over to the next on error.
+func (r *Router) Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
+	var lastErr error
+	for _, p := range r.order() {
+		if !p.Healthy() {
+			continue
+		}
+		start := time.Now()
+		resp, err := p.Complete(ctx, req)
+		p.latency.Store(int64(time.Since(start)))
+		if err == nil {
+			return resp, nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		return CompletionResponse{}, fmt.Errorf("no healthy provider available")
+	}
+	return CompletionResponse{}, fmt.Errorf("all providers failed, last error: %w", lastErr)
+}
+
+// Stream mirrors Complete for the streaming path.
+func (r *Router) Stream(ctx context.Context, req CompletionRequest) (<-chan Delta, error) {
+	var lastErr error
+	for _, p := range r.order() {
+		if !p.Healthy() {
+			continue
+		}
+		start := time.Now()
+		deltas, err := p.Stream(ctx, req)
+		p.latency.Store(int64(time.Since(start)))
+		if err == nil {
+			return deltas, nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		return nil, fmt.Errorf("no healthy provider available")
+	}
+	return nil, fmt.Errorf("all providers failed, last error: %w", lastErr)
+}
@@ -0,0 +1,162 @@
+// Copyright 2023 Linkall Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// fakeProvider is a minimal LLMProvider test double whose behavior is fully
+// controlled by the test.
+type fakeProvider struct {
+	name    string
+	healthy bool
+	delay   time.Duration
+	err     error
+}
+
+func (p *fakeProvider) Name() string  { return p.name }
+func (p *fakeProvider) Healthy() bool { return p.healthy }
+
+func (p *fakeProvider) Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
+	if p.delay > 0 {
+		time.Sleep(p.delay)
+	}
+	if p.err != nil {
+		return CompletionResponse{}, p.err
+	}
+	return CompletionResponse{Content: p.name}, nil
+}
+
+func (p *fakeProvider) Stream(ctx context.Context, req CompletionRequest) (<-chan Delta, error) {
+	return completeAsStream(ctx, p.Complete, req)
+}
+
+func newTrackedFake(p *fakeProvider) *trackedProvider {
+	return &trackedProvider{LLMProvider: p}
+}
+
+func TestRouterPriorityFailsOverToNextHealthy(t *testing.T) {
+	r := &Router{
+		strategy: StrategyPriority,
+		providers: []*trackedProvider{
+			newTrackedFake(&fakeProvider{name: "primary", healthy: true, err: fmt.Errorf("boom")}),
+			newTrackedFake(&fakeProvider{name: "secondary", healthy: true}),
+		},
+	}
+	resp, err := r.Complete(context.Background(), CompletionRequest{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("Complete returned error: %v", err)
+	}
+	if resp.Content != "secondary" {
+		t.Fatalf("expected failover to secondary provider, got %q", resp.Content)
+	}
+}
+
+func TestRouterPrioritySkipsUnhealthy(t *testing.T) {
+	r := &Router{
+		strategy: StrategyPriority,
+		providers: []*trackedProvider{
+			newTrackedFake(&fakeProvider{name: "primary", healthy: false}),
+			newTrackedFake(&fakeProvider{name: "secondary", healthy: true}),
+		},
+	}
+	resp, err := r.Complete(context.Background(), CompletionRequest{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("Complete returned error: %v", err)
+	}
+	if resp.Content != "secondary" {
+		t.Fatalf("expected unhealthy primary to be skipped, got %q", resp.Content)
+	}
+}
+
+func TestRouterAllProvidersFail(t *testing.T) {
+	r := &Router{
+		strategy: StrategyPriority,
+		providers: []*trackedProvider{
+			newTrackedFake(&fakeProvider{name: "primary", healthy: true, err: fmt.Errorf("boom")}),
+		},
+	}
+	if _, err := r.Complete(context.Background(), CompletionRequest{Prompt: "hi"}); err == nil {
+		t.Fatal("expected error when every provider fails")
+	}
+}
+
+func TestRouterNoHealthyProvider(t *testing.T) {
+	r := &Router{
+		strategy: StrategyPriority,
+		providers: []*trackedProvider{
+			newTrackedFake(&fakeProvider{name: "primary", healthy: false}),
+		},
+	}
+	if _, err := r.Complete(context.Background(), CompletionRequest{Prompt: "hi"}); err == nil {
+		t.Fatal("expected error when no provider is healthy")
+	}
+}
+
+func TestRouterRoundRobinRotates(t *testing.T) {
+	r := &Router{
+		strategy: StrategyRoundRobin,
+		providers: []*trackedProvider{
+			newTrackedFake(&fakeProvider{name: "a", healthy: true}),
+			newTrackedFake(&fakeProvider{name: "b", healthy: true}),
+		},
+	}
+	var got []string
+	for i := 0; i < 4; i++ {
+		resp, err := r.Complete(context.Background(), CompletionRequest{Prompt: "hi"})
+		if err != nil {
+			t.Fatalf("Complete returned error: %v", err)
+		}
+		got = append(got, resp.Content)
+	}
+	want := []string{"a", "b", "a", "b"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("round robin order = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestRouterLeastLatencyPrefersFaster(t *testing.T) {
+	r := &Router{
+		strategy: StrategyLeastLatency,
+		providers: []*trackedProvider{
+			newTrackedFake(&fakeProvider{name: "slow", healthy: true, delay: 20 * time.Millisecond}),
+			newTrackedFake(&fakeProvider{name: "fast", healthy: true}),
+		},
+	}
+	// Latencies start at zero for both, so the first call tries "slow"
+	// first (stable tie-break on configuration order) and records its
+	// latency; "fast" is never reached and keeps its zero sample.
+	if _, err := r.Complete(context.Background(), CompletionRequest{Prompt: "warmup"}); err != nil {
+		t.Fatalf("warmup Complete returned error: %v", err)
+	}
+	ordered := r.order()
+	if ordered[0].Name() != "fast" {
+		t.Fatalf("expected fastest provider first, got order %v", providerNames(ordered))
+	}
+}
+
+func providerNames(providers []*trackedProvider) []string {
+	names := make([]string, len(providers))
+	for i, p := range providers {
+		names[i] = p.Name()
+	}
+	return names
+}
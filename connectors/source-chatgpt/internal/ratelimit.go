@@ -0,0 +1,127 @@
+// Copyright 2023 Linkall Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultRateLimitRPS   = 5
+	defaultRateLimitBurst = 10
+	defaultGlobalRPS      = 50
+	defaultGlobalBurst    = 100
+
+	// ipEntryTTL is how long a per-IP limiter may sit idle before it's
+	// evicted. Without this, perIP would grow without bound as distinct
+	// source IPs churn (NAT, IPv6, or an attacker spraying addresses).
+	ipEntryTTL = 10 * time.Minute
+	// ipSweepInterval caps how often limiterFor scans perIP for expired
+	// entries, so eviction doesn't cost a full map scan on every request.
+	ipSweepInterval = time.Minute
+)
+
+// RateLimitConfig configures the per-IP and global token-bucket limiters
+// that protect the (expensive) upstream LLM providers from abusive callers.
+type RateLimitConfig struct {
+	RPS         float64 `json:"rps" yaml:"rps"`
+	Burst       int     `json:"burst" yaml:"burst"`
+	GlobalRPS   float64 `json:"global_rps" yaml:"global_rps"`
+	GlobalBurst int     `json:"global_burst" yaml:"global_burst"`
+}
+
+func (c *RateLimitConfig) init() {
+	if c.RPS <= 0 {
+		c.RPS = defaultRateLimitRPS
+	}
+	if c.Burst <= 0 {
+		c.Burst = defaultRateLimitBurst
+	}
+	if c.GlobalRPS <= 0 {
+		c.GlobalRPS = defaultGlobalRPS
+	}
+	if c.GlobalBurst <= 0 {
+		c.GlobalBurst = defaultGlobalBurst
+	}
+}
+
+// ipLimiterEntry pairs a per-IP limiter with the last time it was touched,
+// so idle entries can be swept out of ipRateLimiter.perIP.
+type ipLimiterEntry struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// ipRateLimiter enforces a token-bucket limit per client IP on top of a
+// separate global cap shared by all callers. Entries idle for longer than
+// ipEntryTTL are evicted so perIP can't grow without bound as source IPs
+// churn.
+type ipRateLimiter struct {
+	cfg    RateLimitConfig
+	global *rate.Limiter
+
+	mu        sync.Mutex
+	perIP     map[string]*ipLimiterEntry
+	lastSweep time.Time
+}
+
+func newIPRateLimiter(cfg RateLimitConfig) *ipRateLimiter {
+	cfg.init()
+	return &ipRateLimiter{
+		cfg:    cfg,
+		global: rate.NewLimiter(rate.Limit(cfg.GlobalRPS), cfg.GlobalBurst),
+		perIP:  make(map[string]*ipLimiterEntry),
+	}
+}
+
+// Allow reports whether a request from ip should be let through right now.
+func (l *ipRateLimiter) Allow(ip string) bool {
+	if !l.global.Allow() {
+		return false
+	}
+	return l.limiterFor(ip).Allow()
+}
+
+func (l *ipRateLimiter) limiterFor(ip string) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.sweepLocked(now)
+	entry, ok := l.perIP[ip]
+	if !ok {
+		entry = &ipLimiterEntry{limiter: rate.NewLimiter(rate.Limit(l.cfg.RPS), l.cfg.Burst)}
+		l.perIP[ip] = entry
+	}
+	entry.lastSeen = now
+	return entry.limiter
+}
+
+// sweepLocked evicts entries idle for longer than ipEntryTTL. The caller
+// must hold l.mu. It's a no-op unless ipSweepInterval has elapsed since the
+// last sweep, so eviction doesn't cost a full map scan on every request.
+func (l *ipRateLimiter) sweepLocked(now time.Time) {
+	if now.Sub(l.lastSweep) < ipSweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for ip, entry := range l.perIP {
+		if now.Sub(entry.lastSeen) > ipEntryTTL {
+			delete(l.perIP, ip)
+		}
+	}
+}
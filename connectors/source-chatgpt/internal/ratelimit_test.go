@@ -0,0 +1,88 @@
+// Copyright 2023 Linkall Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimitConfigInitDefaults(t *testing.T) {
+	var c RateLimitConfig
+	c.init()
+	if c.RPS != defaultRateLimitRPS || c.Burst != defaultRateLimitBurst ||
+		c.GlobalRPS != defaultGlobalRPS || c.GlobalBurst != defaultGlobalBurst {
+		t.Fatalf("init() did not apply defaults: %+v", c)
+	}
+}
+
+func TestIPRateLimiterPerIPBurst(t *testing.T) {
+	l := newIPRateLimiter(RateLimitConfig{RPS: 1, Burst: 2, GlobalRPS: 1000, GlobalBurst: 1000})
+	if !l.Allow("1.1.1.1") || !l.Allow("1.1.1.1") {
+		t.Fatal("expected the configured burst of requests to be allowed")
+	}
+	if l.Allow("1.1.1.1") {
+		t.Fatal("expected request beyond burst to be denied")
+	}
+}
+
+func TestIPRateLimiterIsolatesByIP(t *testing.T) {
+	l := newIPRateLimiter(RateLimitConfig{RPS: 1, Burst: 1, GlobalRPS: 1000, GlobalBurst: 1000})
+	if !l.Allow("1.1.1.1") {
+		t.Fatal("first request from 1.1.1.1 should be allowed")
+	}
+	if l.Allow("1.1.1.1") {
+		t.Fatal("second immediate request from 1.1.1.1 should be denied")
+	}
+	if !l.Allow("2.2.2.2") {
+		t.Fatal("a different IP must have its own independent bucket")
+	}
+}
+
+func TestIPRateLimiterGlobalCapAppliesAcrossIPs(t *testing.T) {
+	l := newIPRateLimiter(RateLimitConfig{RPS: 1000, Burst: 1000, GlobalRPS: 1, GlobalBurst: 1})
+	if !l.Allow("1.1.1.1") {
+		t.Fatal("first request should be allowed under the global cap")
+	}
+	if l.Allow("2.2.2.2") {
+		t.Fatal("second request from a different IP should still be denied by the exhausted global cap")
+	}
+}
+
+func TestIPRateLimiterSweepEvictsIdleEntries(t *testing.T) {
+	l := newIPRateLimiter(RateLimitConfig{RPS: 1, Burst: 1, GlobalRPS: 1000, GlobalBurst: 1000})
+	l.Allow("1.1.1.1")
+	if len(l.perIP) != 1 {
+		t.Fatalf("expected one tracked IP, got %d", len(l.perIP))
+	}
+
+	// Force the next limiterFor call to treat the entry as expired and the
+	// sweep as due, without sleeping ipEntryTTL/ipSweepInterval in a test.
+	l.mu.Lock()
+	l.perIP["1.1.1.1"].lastSeen = time.Now().Add(-2 * ipEntryTTL)
+	l.lastSweep = time.Now().Add(-2 * ipSweepInterval)
+	l.mu.Unlock()
+
+	l.Allow("2.2.2.2")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.perIP["1.1.1.1"]; ok {
+		t.Fatal("expected idle entry for 1.1.1.1 to be evicted by the sweep")
+	}
+	if _, ok := l.perIP["2.2.2.2"]; !ok {
+		t.Fatal("expected entry for 2.2.2.2 to remain after the sweep")
+	}
+}
@@ -16,17 +16,29 @@ package internal
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	ce "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/client"
+	"github.com/cloudevents/sdk-go/v2/protocol"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	cdkgo "github.com/vanus-labs/cdk-go"
 	"github.com/vanus-labs/cdk-go/log"
+
+	"github.com/vanus-labs/vanus-connect/connectors/source-chatgpt/internal/provider"
+	"github.com/vanus-labs/vanus-connect/connectors/source-chatgpt/internal/tracing"
+	"github.com/vanus-labs/vanus-connect/pkg/ipresolver"
 )
 
 const (
@@ -34,8 +46,17 @@ const (
 	defaultEventSource = "vanus-chatGPT-source"
 	headerSource       = "vanus-source"
 	headerType         = "vanus-type"
+	headerStream       = "vanus-stream"
+
+	extensionSequence      = "sequence"
+	extensionCorrelationID = "correlationid"
+	extensionClientIP      = "clientip"
 )
 
+type contextKey string
+
+const clientIPContextKey contextKey = "clientip"
+
 var _ cdkgo.Source = &chatGPTSource{}
 
 func NewChatGPTSource() cdkgo.Source {
@@ -45,42 +66,120 @@ func NewChatGPTSource() cdkgo.Source {
 }
 
 type chatGPTSource struct {
-	config  *chatGPTConfig
-	events  chan *cdkgo.Tuple
-	number  int
-	day     string
-	lock    sync.Mutex
-	server  *http.Server
-	service *chatGPTService
+	config     *chatGPTConfig
+	events     chan *cdkgo.Tuple
+	number     int
+	day        string
+	lock       sync.Mutex
+	client     ce.Client
+	cancel     context.CancelFunc
+	shutdownTP func(context.Context) error
+	router     *provider.Router
+	ipResolver *ipresolver.Resolver
+	limiter    *ipRateLimiter
 }
 
 func (s *chatGPTSource) Initialize(ctx context.Context, cfg cdkgo.ConfigAccessor) error {
 	s.config = cfg.(*chatGPTConfig)
 	s.config.Init()
-	s.service = newChatGPTService(s.config)
-	s.server = &http.Server{
-		Addr:    fmt.Sprintf(":%d", s.config.Port),
-		Handler: s,
+
+	router, err := provider.NewRouter(s.config.Routing)
+	if err != nil {
+		return fmt.Errorf("failed to build provider router: %w", err)
 	}
+	s.router = router
+
+	shutdownTP, err := tracing.Init(ctx, s.config.Tracing)
+	if err != nil {
+		return fmt.Errorf("failed to initialize tracing: %w", err)
+	}
+	s.shutdownTP = shutdownTP
+
+	s.ipResolver = ipresolver.NewResolver(s.config.TrustedProxies)
+	s.limiter = newIPRateLimiter(s.config.RateLimit)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.healthz)
+
+	p, err := cehttp.New(
+		cehttp.WithPort(s.config.Port),
+		cehttp.WithPath("/"),
+		cehttp.WithMiddleware(s.rateLimitMiddleware),
+		cehttp.WithRequestDataAtContextMiddleware(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create cloudevents http protocol: %w", err)
+	}
+	p.Handler = mux
+	c, err := ce.NewClient(p, client.WithTimeNow(), client.WithUUIDs())
+	if err != nil {
+		return fmt.Errorf("failed to create cloudevents client: %w", err)
+	}
+	s.client = c
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
 	go func() {
 		log.Info("http server is ready to start", map[string]interface{}{
 			"port": s.config.Port,
 		})
-		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			panic(fmt.Sprintf("cloud not listen on %d, error:%s", s.config.Port, err.Error()))
+		if err := c.StartReceiver(runCtx, s.receive); err != nil {
+			log.Warning("cloudevents receiver stopped with error", map[string]interface{}{
+				log.KeyError: err,
+			})
 		}
 		log.Info("http server stopped", nil)
 	}()
 	return nil
 }
 
+// rateLimitMiddleware resolves the real client IP for every request,
+// rejecting it with 429 and a Retry-After header once the per-IP or global
+// token bucket is exhausted, and otherwise attaches the resolved IP to the
+// request context so receive/receiveStream can set it as the clientip
+// CloudEvents extension.
+func (s *chatGPTSource) rateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ip := s.ipResolver.Resolve(req)
+		if !s.limiter.Allow(ip) {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		req = req.WithContext(context.WithValue(req.Context(), clientIPContextKey, ip))
+		next.ServeHTTP(w, req)
+	})
+}
+
+func clientIPFrom(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPContextKey).(string)
+	return ip
+}
+
+// healthz reports the current health (circuit breaker state) of every
+// configured LLM provider.
+func (s *chatGPTSource) healthz(w http.ResponseWriter, _ *http.Request) {
+	health := s.router.Health()
+	w.Header().Set("Content-Type", "application/json")
+	for _, healthy := range health {
+		if !healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			break
+		}
+	}
+	_ = json.NewEncoder(w).Encode(health)
+}
+
 func (s *chatGPTSource) Name() string {
 	return "ChatGPTSource"
 }
 
 func (s *chatGPTSource) Destroy() error {
-	if s.server != nil {
-		s.server.Shutdown(context.Background())
+	if s.cancel != nil {
+		s.cancel()
+	}
+	if s.shutdownTP != nil {
+		return s.shutdownTP(context.Background())
 	}
 	return nil
 }
@@ -89,54 +188,232 @@ func (s *chatGPTSource) Chan() <-chan *cdkgo.Tuple {
 	return s.events
 }
 
-func (s *chatGPTSource) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	body, err := ioutil.ReadAll(req.Body)
-	if err != nil || len(body) == 0 {
-		w.WriteHeader(http.StatusBadRequest)
-		return
+// receive is invoked by the CloudEvents HTTP protocol binding for every
+// request, in either binary or structured content mode. The prompt is taken
+// from the incoming event's data, also accepting a plain-text body for
+// back-compat with callers that don't speak CloudEvents yet. The returned
+// event becomes the response body, with proper ce-id/ce-source/ce-type/ce-time
+// headers set by the binding.
+func (s *chatGPTSource) receive(ctx context.Context, event ce.Event) (*ce.Event, protocol.Result) {
+	var header http.Header
+	if reqData := cehttp.RequestDataFromContext(ctx); reqData != nil {
+		header = reqData.Header
+		ctx = tracing.ExtractHTTPHeaders(ctx, header)
+	}
+
+	prompt, err := promptFromEvent(event)
+	if err != nil {
+		return nil, cehttp.NewResult(http.StatusBadRequest, "invalid request: %w", err)
+	}
+
+	ctx, span := tracing.Tracer().Start(ctx, "chatgpt.completion", trace.WithAttributes(
+		attribute.String("model", s.config.Model),
+		attribute.Int("prompt.tokens", len(strings.Fields(prompt))),
+	))
+	defer span.End()
+
+	stream := s.config.Stream
+	if header != nil {
+		if v := header.Get(headerStream); v != "" {
+			stream, _ = strconv.ParseBool(v)
+		}
 	}
-	content, err := s.service.CreateChatCompletion(string(body))
+	if stream {
+		return s.receiveStream(ctx, prompt, header)
+	}
+
+	resp, err := s.router.Complete(ctx, provider.CompletionRequest{Prompt: prompt})
 	if err != nil {
-		log.Warning("failed to get content from ChatGPT", map[string]interface{}{
+		span.SetStatus(codes.Error, err.Error())
+		log.Warning("failed to get content from LLM provider", map[string]interface{}{
 			log.KeyError: err,
 		})
+		return nil, cehttp.NewResult(http.StatusInternalServerError, "failed to call LLM provider: %w", err)
 	}
-	eventSource := req.Header.Get(headerSource)
-	if eventSource == "" {
-		eventSource = defaultEventSource
-	}
-	eventType := req.Header.Get(headerType)
-	if eventType == "" {
-		eventType = defaultEventType
-	}
-	event := ce.NewEvent()
-	event.SetID(uuid.New().String())
-	event.SetTime(time.Now())
-	event.SetType(eventType)
-	event.SetSource(eventSource)
-	event.SetData(ce.ApplicationJSON, map[string]string{
-		"content": content,
+	span.SetStatus(codes.Ok, "")
+
+	eventType, eventSource := eventTypeAndSource(header)
+	out := ce.NewEvent()
+	out.SetID(uuid.New().String())
+	out.SetTime(time.Now())
+	out.SetType(eventType)
+	out.SetSource(eventSource)
+	setTraceExtensions(ctx, &out)
+	setClientIPExtension(ctx, &out)
+	out.SetData(ce.ApplicationJSON, map[string]string{
+		"content": resp.Content,
 	})
+
+	if ackErr := s.publish(&out); ackErr != nil {
+		log.Warning("failed to send event to target", map[string]interface{}{
+			log.KeyError: ackErr,
+		})
+		return nil, cehttp.NewResult(http.StatusInternalServerError, "failed to send event to target: %w", ackErr)
+	}
+	return &out, ce.ResultACK
+}
+
+// setTraceExtensions sets the CloudEvents distributed tracing extension
+// (traceparent/tracestate) on event from the span carried by ctx, so
+// downstream sinks can continue the trace.
+func setTraceExtensions(ctx context.Context, event *ce.Event) {
+	traceparent, tracestate := tracing.CarrierFrom(ctx)
+	if traceparent != "" {
+		event.SetExtension("traceparent", traceparent)
+	}
+	if tracestate != "" {
+		event.SetExtension("tracestate", tracestate)
+	}
+}
+
+// setClientIPExtension sets the clientip CloudEvents extension on event from
+// the IP resolved by rateLimitMiddleware, if any.
+func setClientIPExtension(ctx context.Context, event *ce.Event) {
+	if ip := clientIPFrom(ctx); ip != "" {
+		event.SetExtension(extensionClientIP, ip)
+	}
+}
+
+// eventTypeAndSource resolves the outgoing event's type/source, honoring the
+// vanus-type/vanus-source header overrides callers could set before this
+// source adopted the CloudEvents HTTP binding, falling back to
+// defaultEventType/defaultEventSource when unset.
+func eventTypeAndSource(header http.Header) (eventType, eventSource string) {
+	eventType, eventSource = defaultEventType, defaultEventSource
+	if header == nil {
+		return eventType, eventSource
+	}
+	if v := header.Get(headerType); v != "" {
+		eventType = v
+	}
+	if v := header.Get(headerSource); v != "" {
+		eventSource = v
+	}
+	return eventType, eventSource
+}
+
+// receiveStream mirrors receive but for stream: true requests. Each delta
+// chunk from ChatGPT is published onto s.events as its own CloudEvent,
+// carrying a sequence extension (monotonic per request) and a
+// correlationid extension shared across the whole response. The response is
+// held until the upstream stream closes; if any chunk failed to reach the
+// target, the last ack error seen is returned as a NACK instead of the
+// aggregated completion.
+func (s *chatGPTSource) receiveStream(ctx context.Context, prompt string, header http.Header) (*ce.Event, protocol.Result) {
+	eventType, eventSource := eventTypeAndSource(header)
+
+	deltas, err := s.router.Stream(ctx, provider.CompletionRequest{Prompt: prompt})
+	if err != nil {
+		log.Warning("failed to start LLM provider stream", map[string]interface{}{
+			log.KeyError: err,
+		})
+		return nil, cehttp.NewResult(http.StatusInternalServerError, "failed to start LLM provider stream: %w", err)
+	}
+
+	correlationID := uuid.New().String()
+	sequence := 0
+	var content strings.Builder
+	var finishReason string
+	var ackErr error
+
+	for {
+		select {
+		case delta, ok := <-deltas:
+			if !ok {
+				if ackErr != nil {
+					return nil, cehttp.NewResult(http.StatusInternalServerError, "failed to send event to target: %w", ackErr)
+				}
+				out := ce.NewEvent()
+				out.SetID(uuid.New().String())
+				out.SetTime(time.Now())
+				out.SetType(eventType)
+				out.SetSource(eventSource)
+				out.SetExtension(extensionCorrelationID, correlationID)
+				setTraceExtensions(ctx, &out)
+				setClientIPExtension(ctx, &out)
+				out.SetData(ce.ApplicationJSON, map[string]string{
+					"content":       content.String(),
+					"finish_reason": finishReason,
+				})
+				return &out, ce.ResultACK
+			}
+			if delta.Err != nil {
+				log.Warning("LLM provider stream ended prematurely", map[string]interface{}{
+					log.KeyError:    delta.Err,
+					"correlationid": correlationID,
+				})
+				return nil, cehttp.NewResult(http.StatusInternalServerError, "LLM provider stream ended prematurely: %w", delta.Err)
+			}
+			sequence++
+			content.WriteString(delta.Content)
+			finishReason = delta.FinishReason
+
+			chunk := ce.NewEvent()
+			chunk.SetID(uuid.New().String())
+			chunk.SetTime(time.Now())
+			chunk.SetType(eventType)
+			chunk.SetSource(eventSource)
+			chunk.SetExtension(extensionSequence, sequence)
+			chunk.SetExtension(extensionCorrelationID, correlationID)
+			setTraceExtensions(ctx, &chunk)
+			setClientIPExtension(ctx, &chunk)
+			data := map[string]string{"content": delta.Content}
+			if delta.FinishReason != "" {
+				data["finish_reason"] = delta.FinishReason
+			}
+			chunk.SetData(ce.ApplicationJSON, data)
+
+			if err := s.publish(&chunk); err != nil {
+				log.Warning("failed to send stream chunk to target", map[string]interface{}{
+					log.KeyError: err,
+				})
+				ackErr = err
+			}
+		case <-ctx.Done():
+			log.Info("client disconnected, stopping ChatGPT stream", map[string]interface{}{
+				"correlationid": correlationID,
+			})
+			return nil, cehttp.NewResult(http.StatusRequestTimeout, "client disconnected")
+		}
+	}
+}
+
+// publish pushes event onto s.events and blocks until it has been acked by
+// the target via Success/Failed.
+func (s *chatGPTSource) publish(event *ce.Event) error {
 	wg := sync.WaitGroup{}
 	wg.Add(1)
+	var ackErr error
 	s.events <- &cdkgo.Tuple{
-		Event: &event,
+		Event: event,
 		Success: func() {
 			defer wg.Done()
-			w.WriteHeader(http.StatusOK)
-			if err != nil {
-				w.Write([]byte(err.Error()))
-			}
-			log.Info("send event to target success", nil)
 		},
-		Failed: func(err2 error) {
+		Failed: func(err error) {
 			defer wg.Done()
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte("send event to target error: " + err2.Error()))
-			log.Warning("failed to send event to target", map[string]interface{}{
-				log.KeyError: err2,
-			})
+			ackErr = err
 		},
 	}
 	wg.Wait()
+	return ackErr
+}
+
+// promptFromEvent extracts the ChatGPT prompt from an incoming CloudEvent.
+// A JSON body of the form {"prompt": "..."} is preferred; any other body,
+// including a plain-text one, is used verbatim as the prompt for back-compat
+// with callers that predate the CloudEvents binding.
+func promptFromEvent(event ce.Event) (string, error) {
+	data := event.Data()
+	if len(data) == 0 {
+		return "", fmt.Errorf("empty request body")
+	}
+	if event.DataContentType() == ce.ApplicationJSON {
+		var payload struct {
+			Prompt string `json:"prompt"`
+		}
+		if err := event.DataAs(&payload); err == nil && payload.Prompt != "" {
+			return payload.Prompt, nil
+		}
+	}
+	return string(data), nil
 }
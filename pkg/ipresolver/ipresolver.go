@@ -0,0 +1,97 @@
+// Copyright 2023 Linkall Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ipresolver resolves the real client IP of an inbound HTTP request
+// behind one or more reverse proxies. It is shared across connectors that
+// need to attribute requests to the originating client rather than the
+// proxy that forwarded them.
+package ipresolver
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Resolver resolves the real client IP for a request, trusting the
+// X-Real-IP and X-Forwarded-For headers only when the peer that sent the
+// request is within a configured set of trusted proxy CIDRs. Headers from
+// an untrusted peer are ignored, since they can be spoofed by the caller.
+type Resolver struct {
+	trusted []*net.IPNet
+}
+
+// NewResolver builds a Resolver that trusts the given proxy CIDRs. Entries
+// that fail to parse are skipped.
+func NewResolver(trustedCIDRs []string) *Resolver {
+	r := &Resolver{}
+	for _, cidr := range trustedCIDRs {
+		_, ipnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			continue
+		}
+		r.trusted = append(r.trusted, ipnet)
+	}
+	return r
+}
+
+func (r *Resolver) isTrusted(ip net.IP) bool {
+	for _, n := range r.trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns the real client IP for req. If the immediate peer
+// (req.RemoteAddr) isn't a trusted proxy, its address is returned as-is and
+// any forwarding headers are ignored. Otherwise X-Real-IP is preferred, and
+// failing that the rightmost entry of X-Forwarded-For that isn't itself a
+// trusted proxy, since that's the first hop added by an untrusted party.
+func (r *Resolver) Resolve(req *http.Request) string {
+	remote := hostOf(req.RemoteAddr)
+	peer := net.ParseIP(remote)
+	if peer == nil || !r.isTrusted(peer) {
+		return remote
+	}
+
+	if real := strings.TrimSpace(req.Header.Get("X-Real-IP")); real != "" {
+		return real
+	}
+
+	if xff := req.Header.Get("X-Forwarded-For"); xff != "" {
+		hops := strings.Split(xff, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			ip := net.ParseIP(hop)
+			if ip == nil {
+				continue
+			}
+			if !r.isTrusted(ip) {
+				return hop
+			}
+		}
+	}
+
+	return remote
+}
+
+func hostOf(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
@@ -0,0 +1,91 @@
+// Copyright 2023 Linkall Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipresolver
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestResolveUntrustedPeerIgnoresHeaders(t *testing.T) {
+	r := NewResolver([]string{"10.0.0.0/8"})
+	req := &http.Request{
+		RemoteAddr: "203.0.113.5:1234",
+		Header: http.Header{
+			"X-Real-Ip": []string{"198.51.100.9"},
+		},
+	}
+	if got := r.Resolve(req); got != "203.0.113.5" {
+		t.Fatalf("Resolve() = %q, want the untrusted peer address unchanged", got)
+	}
+}
+
+func TestResolveTrustedPeerPrefersXRealIP(t *testing.T) {
+	r := NewResolver([]string{"10.0.0.0/8"})
+	req := &http.Request{
+		RemoteAddr: "10.0.0.1:1234",
+		Header: http.Header{
+			"X-Real-Ip":       []string{"198.51.100.9"},
+			"X-Forwarded-For": []string{"198.51.100.10"},
+		},
+	}
+	if got := r.Resolve(req); got != "198.51.100.9" {
+		t.Fatalf("Resolve() = %q, want X-Real-IP to take precedence", got)
+	}
+}
+
+func TestResolveTrustedPeerUsesRightmostUntrustedXFF(t *testing.T) {
+	r := NewResolver([]string{"10.0.0.0/8"})
+	req := &http.Request{
+		RemoteAddr: "10.0.0.1:1234",
+		Header: http.Header{
+			"X-Forwarded-For": []string{"198.51.100.9, 203.0.113.5, 10.0.0.2"},
+		},
+	}
+	if got := r.Resolve(req); got != "203.0.113.5" {
+		t.Fatalf("Resolve() = %q, want the rightmost untrusted XFF hop", got)
+	}
+}
+
+func TestResolveTrustedPeerFallsBackToRemoteAddr(t *testing.T) {
+	r := NewResolver([]string{"10.0.0.0/8"})
+	req := &http.Request{
+		RemoteAddr: "10.0.0.1:1234",
+		Header:     http.Header{},
+	}
+	if got := r.Resolve(req); got != "10.0.0.1" {
+		t.Fatalf("Resolve() = %q, want RemoteAddr fallback", got)
+	}
+}
+
+func TestResolveAllXFFHopsTrustedFallsBackToRemoteAddr(t *testing.T) {
+	r := NewResolver([]string{"10.0.0.0/8"})
+	req := &http.Request{
+		RemoteAddr: "10.0.0.1:1234",
+		Header: http.Header{
+			"X-Forwarded-For": []string{"10.0.0.2, 10.0.0.3"},
+		},
+	}
+	if got := r.Resolve(req); got != "10.0.0.1" {
+		t.Fatalf("Resolve() = %q, want RemoteAddr fallback when every XFF hop is trusted", got)
+	}
+}
+
+func TestNewResolverSkipsInvalidCIDR(t *testing.T) {
+	r := NewResolver([]string{"not-a-cidr", "10.0.0.0/8"})
+	if len(r.trusted) != 1 {
+		t.Fatalf("expected exactly one valid trusted CIDR, got %d", len(r.trusted))
+	}
+}